@@ -0,0 +1,66 @@
+package metrics
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	// MetricUnidlingEventsTotal counts empty-lb-backends events handled by
+	// the unidling controller, labeled by the service they resolved to and
+	// the outcome ("need_pods" or "unknown_vip").
+	MetricUnidlingEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ovnkube",
+		Subsystem: "unidling",
+		Name:      "events_total",
+		Help:      "The number of empty-lb-backends events handled by the unidling controller.",
+	}, []string{"service", "namespace", "result"})
+
+	// MetricUnidlingTransitionsTotal counts idle-status transitions, fed by
+	// markServiceAs{IdleIfNeeded,GracePeriod,NotIdle}.
+	MetricUnidlingTransitionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "ovnkube",
+		Subsystem: "unidling",
+		Name:      "transitions_total",
+		Help:      "The number of service idle-status transitions made by the unidling controllers.",
+	}, []string{"from", "to"})
+
+	// MetricServicesIdle is the current count of services in the Idle or
+	// GracePeriod status.
+	MetricServicesIdle = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "ovnkube",
+		Subsystem: "unidling",
+		Name:      "services_idle",
+		Help:      "The number of services currently idled.",
+	})
+
+	// MetricUnidlingActivationSeconds observes the wall-clock delta between
+	// an empty-lb-backends event for a service and the moment its first
+	// ready endpoint reappears.
+	MetricUnidlingActivationSeconds = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "ovnkube",
+		Subsystem: "unidling",
+		Name:      "activation_seconds",
+		Help:      "Time from an empty-lb-backends event to the first ready endpoint reappearing for that service.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	// MetricSyncServiceLatency observes how long handleGracePeriodEndEvent
+	// takes to process a single service once its grace period ends.
+	MetricSyncServiceLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: "ovnkube",
+		Subsystem: "unidling",
+		Name:      "sync_service_latency_seconds",
+		Help:      "Time to process a service's grace-period-end sync.",
+		Buckets:   prometheus.DefBuckets,
+	})
+)
+
+// RegisterUnidlingMetrics registers the unidling controllers' Prometheus
+// collectors. Call once during ovnkube-controller metrics setup.
+func RegisterUnidlingMetrics() {
+	prometheus.MustRegister(
+		MetricUnidlingEventsTotal,
+		MetricUnidlingTransitionsTotal,
+		MetricServicesIdle,
+		MetricUnidlingActivationSeconds,
+		MetricSyncServiceLatency,
+	)
+}
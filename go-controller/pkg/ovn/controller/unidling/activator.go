@@ -0,0 +1,256 @@
+package unidling
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	kapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// IdledAtReplicasAnnotation records the replica count a workload had
+	// right before it was scaled to zero, so an Activator can restore it
+	// verbatim once the Service needs pods again.
+	IdledAtReplicasAnnotation = "k8s.ovn.org/idled-at-replicas"
+
+	// IdledTargetAnnotation pins the workload an Activator should scale, in
+	// "kind/name" form (e.g. "Deployment/my-app"), for Services whose
+	// selector can't be mapped back to a single owning workload.
+	IdledTargetAnnotation = "k8s.ovn.org/idled-target"
+)
+
+// Activator brings the workload backing a Service back from zero replicas
+// once the Service needs pods again.
+type Activator interface {
+	// Activate resolves the workload fronted by svc and restores its
+	// replica count. It is a no-op if svc carries no idled-at-replicas
+	// annotation, or if the target workload can't be determined.
+	Activate(svc *kapi.Service) error
+}
+
+// WorkloadResolver maps a Service to the Deployment, StatefulSet or
+// ReplicaSet backing it, and reads/writes that workload's replica count.
+// Construct one with NewWorkloadResolver and share it between
+// NewWorkloadActivator and NewAutoIdlerController, so both agree on how a
+// Service maps to a workload.
+type WorkloadResolver struct {
+	kubeClient   kubernetes.Interface
+	deployLister appslisters.DeploymentLister
+	ssLister     appslisters.StatefulSetLister
+	rsLister     appslisters.ReplicaSetLister
+}
+
+// NewWorkloadResolver returns a resolver backed by the given listers.
+func NewWorkloadResolver(kubeClient kubernetes.Interface, deployLister appslisters.DeploymentLister, ssLister appslisters.StatefulSetLister, rsLister appslisters.ReplicaSetLister) *WorkloadResolver {
+	return &WorkloadResolver{
+		kubeClient:   kubeClient,
+		deployLister: deployLister,
+		ssLister:     ssLister,
+		rsLister:     rsLister,
+	}
+}
+
+// resolveTarget returns the kind ("Deployment", "StatefulSet" or
+// "ReplicaSet") and name of the workload backing svc, preferring the
+// explicit IdledTargetAnnotation over selector matching.
+//
+// Selector matching compares svc.Spec.Selector against each candidate
+// workload's own spec.selector, not against live Pods: Activate is only
+// ever called once a Service has been scaled to zero, by which point no
+// Pods matching the selector exist, while the workload's selector persists
+// regardless of its replica count.
+func (r *WorkloadResolver) resolveTarget(svc *kapi.Service) (string, string, error) {
+	if target, ok := svc.Annotations[IdledTargetAnnotation]; ok && target != "" {
+		parts := strings.SplitN(target, "/", 2)
+		if len(parts) != 2 {
+			return "", "", fmt.Errorf("invalid %s annotation %q, expected kind/name", IdledTargetAnnotation, target)
+		}
+		return parts[0], parts[1], nil
+	}
+
+	if len(svc.Spec.Selector) == 0 {
+		return "", "", nil
+	}
+
+	svcLabels := labels.Set(svc.Spec.Selector)
+
+	deployments, err := r.deployLister.Deployments(svc.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", "", err
+	}
+	for _, d := range deployments {
+		if selectorMatches(d.Spec.Selector, svcLabels) {
+			return "Deployment", d.Name, nil
+		}
+	}
+
+	statefulSets, err := r.ssLister.StatefulSets(svc.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", "", err
+	}
+	for _, s := range statefulSets {
+		if selectorMatches(s.Spec.Selector, svcLabels) {
+			return "StatefulSet", s.Name, nil
+		}
+	}
+
+	replicaSets, err := r.rsLister.ReplicaSets(svc.Namespace).List(labels.Everything())
+	if err != nil {
+		return "", "", err
+	}
+	for _, rs := range replicaSets {
+		if owner := metav1.GetControllerOf(rs); owner != nil && owner.Kind == "Deployment" {
+			// Owned by a Deployment, already matched above.
+			continue
+		}
+		if selectorMatches(rs.Spec.Selector, svcLabels) {
+			return "ReplicaSet", rs.Name, nil
+		}
+	}
+
+	return "", "", nil
+}
+
+// selectorMatches reports whether a workload's spec.selector matches the
+// Service's selector labels.
+func selectorMatches(workloadSelector *metav1.LabelSelector, svcLabels labels.Set) bool {
+	sel, err := metav1.LabelSelectorAsSelector(workloadSelector)
+	if err != nil {
+		return false
+	}
+	return sel.Matches(svcLabels)
+}
+
+// currentReplicas returns the live spec.replicas of the named workload.
+func (r *WorkloadResolver) currentReplicas(namespace, kind, name string) (int32, error) {
+	switch kind {
+	case "Deployment":
+		d, err := r.deployLister.Deployments(namespace).Get(name)
+		if err != nil {
+			return 0, err
+		}
+		return pointerInt32(d.Spec.Replicas), nil
+	case "StatefulSet":
+		s, err := r.ssLister.StatefulSets(namespace).Get(name)
+		if err != nil {
+			return 0, err
+		}
+		return pointerInt32(s.Spec.Replicas), nil
+	case "ReplicaSet":
+		rs, err := r.rsLister.ReplicaSets(namespace).Get(name)
+		if err != nil {
+			return 0, err
+		}
+		return pointerInt32(rs.Spec.Replicas), nil
+	default:
+		return 0, fmt.Errorf("unsupported workload kind %q", kind)
+	}
+}
+
+// scaleTo patches the named workload's spec.replicas, skipping the API call
+// if it's already at the desired count.
+func (r *WorkloadResolver) scaleTo(namespace, kind, name string, replicas int32) error {
+	current, err := r.currentReplicas(namespace, kind, name)
+	if err != nil {
+		return err
+	}
+	if current == replicas {
+		return nil
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	ctx := context.Background()
+
+	switch kind {
+	case "Deployment":
+		_, err = r.kubeClient.AppsV1().Deployments(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "StatefulSet":
+		_, err = r.kubeClient.AppsV1().StatefulSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	case "ReplicaSet":
+		_, err = r.kubeClient.AppsV1().ReplicaSets(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	default:
+		return fmt.Errorf("unsupported workload kind %q", kind)
+	}
+	return err
+}
+
+func pointerInt32(i *int32) int32 {
+	if i == nil {
+		return 1
+	}
+	return *i
+}
+
+// workloadActivator is the default Activator. It resolves the workload
+// backing a Service and patches its spec.replicas back to the value
+// recorded at idle time.
+type workloadActivator struct {
+	*WorkloadResolver
+}
+
+// NewWorkloadActivator returns the default Activator implementation, backed
+// by resolver.
+func NewWorkloadActivator(resolver *WorkloadResolver) Activator {
+	return &workloadActivator{WorkloadResolver: resolver}
+}
+
+func (a *workloadActivator) Activate(svc *kapi.Service) error {
+	replicas, ok, err := idledReplicas(svc)
+	if err != nil {
+		return fmt.Errorf("couldn't parse %s annotation on service %s/%s: %v", IdledAtReplicasAnnotation, svc.Namespace, svc.Name, err)
+	}
+	if !ok {
+		return nil
+	}
+
+	kind, name, err := a.resolveTarget(svc)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve idled target for service %s/%s: %v", svc.Namespace, svc.Name, err)
+	}
+	if kind == "" {
+		klog.Warningf("Could not determine the workload backing service %s/%s, skipping activation", svc.Namespace, svc.Name)
+		return nil
+	}
+
+	klog.V(5).Infof("Scaling %s %s/%s back to %d replicas to serve service %s/%s", kind, svc.Namespace, name, replicas, svc.Namespace, svc.Name)
+	if err := a.scaleTo(svc.Namespace, kind, name, replicas); err != nil {
+		return err
+	}
+
+	if err := a.clearIdleAnnotations(svc); err != nil {
+		return fmt.Errorf("couldn't clear idle annotations on service %s/%s: %v", svc.Namespace, svc.Name, err)
+	}
+	return nil
+}
+
+// clearIdleAnnotations removes the annotations idleService stamped when svc
+// was scaled to zero, now that it's back up. Without this, idleStatusController
+// (driven by IdledAtAnnotation's presence) would report the service as
+// permanently Idle, and autoIdlerController would refuse to ever idle it
+// again, since it only re-arms services whose status is StatusNotIdle.
+func (r *WorkloadResolver) clearIdleAnnotations(svc *kapi.Service) error {
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null,%q:null,%q:null}}}`,
+		IdledAtAnnotation, IdledAtReplicasAnnotation, IdledTargetAnnotation))
+	_, err := r.kubeClient.CoreV1().Services(svc.Namespace).Patch(context.Background(), svc.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func idledReplicas(svc *kapi.Service) (int32, bool, error) {
+	raw, ok := svc.Annotations[IdledAtReplicasAnnotation]
+	if !ok || raw == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseInt(raw, 10, 32)
+	if err != nil {
+		return 0, false, err
+	}
+	return int32(n), true, nil
+}
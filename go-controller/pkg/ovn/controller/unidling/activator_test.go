@@ -0,0 +1,184 @@
+package unidling
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	kapi "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	"k8s.io/client-go/tools/cache"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newDeploymentLister(deployments ...*appsv1.Deployment) appslisters.DeploymentLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, d := range deployments {
+		_ = indexer.Add(d)
+	}
+	return appslisters.NewDeploymentLister(indexer)
+}
+
+func newStatefulSetLister(statefulSets ...*appsv1.StatefulSet) appslisters.StatefulSetLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, s := range statefulSets {
+		_ = indexer.Add(s)
+	}
+	return appslisters.NewStatefulSetLister(indexer)
+}
+
+func newReplicaSetLister(replicaSets ...*appsv1.ReplicaSet) appslisters.ReplicaSetLister {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	for _, rs := range replicaSets {
+		_ = indexer.Add(rs)
+	}
+	return appslisters.NewReplicaSetLister(indexer)
+}
+
+func TestResolveTargetPrefersExplicitAnnotation(t *testing.T) {
+	resolver := NewWorkloadResolver(fake.NewSimpleClientset(), newDeploymentLister(), newStatefulSetLister(), newReplicaSetLister())
+
+	svc := &kapi.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "svc",
+			Annotations: map[string]string{IdledTargetAnnotation: "StatefulSet/web"},
+		},
+	}
+
+	kind, name, err := resolver.resolveTarget(svc)
+	if err != nil {
+		t.Fatalf("resolveTarget returned error: %v", err)
+	}
+	if kind != "StatefulSet" || name != "web" {
+		t.Fatalf("got %s/%s, want StatefulSet/web", kind, name)
+	}
+}
+
+func TestResolveTargetMatchesWorkloadSelectorAtZeroReplicas(t *testing.T) {
+	zero := int32(0)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &zero,
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+		},
+	}
+	resolver := NewWorkloadResolver(fake.NewSimpleClientset(), newDeploymentLister(deploy), newStatefulSetLister(), newReplicaSetLister())
+
+	svc := &kapi.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec:       kapi.ServiceSpec{Selector: map[string]string{"app": "app"}},
+	}
+
+	kind, name, err := resolver.resolveTarget(svc)
+	if err != nil {
+		t.Fatalf("resolveTarget returned error: %v", err)
+	}
+	if kind != "Deployment" || name != "app" {
+		t.Fatalf("got %s/%s, want Deployment/app (selector match must survive zero replicas)", kind, name)
+	}
+}
+
+func TestResolveTargetSkipsReplicaSetOwnedByDeployment(t *testing.T) {
+	rs := &appsv1.ReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "app-abc123",
+			OwnerReferences: []metav1.OwnerReference{
+				{Kind: "Deployment", Name: "app", Controller: boolPtr(true)},
+			},
+		},
+		Spec: appsv1.ReplicaSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "app"}},
+		},
+	}
+	resolver := NewWorkloadResolver(fake.NewSimpleClientset(), newDeploymentLister(), newStatefulSetLister(), newReplicaSetLister(rs))
+
+	svc := &kapi.Service{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"},
+		Spec:       kapi.ServiceSpec{Selector: map[string]string{"app": "app"}},
+	}
+
+	kind, _, err := resolver.resolveTarget(svc)
+	if err != nil {
+		t.Fatalf("resolveTarget returned error: %v", err)
+	}
+	if kind != "" {
+		t.Fatalf("got kind %q, want \"\" (a Deployment-owned ReplicaSet shouldn't be picked directly)", kind)
+	}
+}
+
+func TestResolveTargetNoSelectorNoMatch(t *testing.T) {
+	resolver := NewWorkloadResolver(fake.NewSimpleClientset(), newDeploymentLister(), newStatefulSetLister(), newReplicaSetLister())
+
+	svc := &kapi.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "svc"}}
+
+	kind, name, err := resolver.resolveTarget(svc)
+	if err != nil {
+		t.Fatalf("resolveTarget returned error: %v", err)
+	}
+	if kind != "" || name != "" {
+		t.Fatalf("got %s/%s, want empty result for a selector-less service", kind, name)
+	}
+}
+
+func TestScaleToSkipsPatchWhenAlreadyAtTarget(t *testing.T) {
+	three := int32(3)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &three},
+	}
+	client := fake.NewSimpleClientset(deploy)
+	resolver := NewWorkloadResolver(client, newDeploymentLister(deploy), newStatefulSetLister(), newReplicaSetLister())
+
+	client.PrependReactor("patch", "deployments", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		t.Fatalf("scaleTo should not patch when already at the desired replica count")
+		return false, nil, nil
+	})
+
+	if err := resolver.scaleTo("ns", "Deployment", "app", 3); err != nil {
+		t.Fatalf("scaleTo returned error: %v", err)
+	}
+}
+
+func TestActivateClearsIdleAnnotations(t *testing.T) {
+	two := int32(2)
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "app"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &two},
+	}
+	svc := &kapi.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "ns",
+			Name:      "svc",
+			Annotations: map[string]string{
+				IdledAtAnnotation:         "2026-01-01T00:00:00Z",
+				IdledAtReplicasAnnotation: "2",
+				IdledTargetAnnotation:     "Deployment/app",
+			},
+		},
+	}
+	client := fake.NewSimpleClientset(deploy, svc)
+	resolver := NewWorkloadResolver(client, newDeploymentLister(deploy), newStatefulSetLister(), newReplicaSetLister())
+	activator := NewWorkloadActivator(resolver)
+
+	if err := activator.Activate(svc); err != nil {
+		t.Fatalf("Activate returned error: %v", err)
+	}
+
+	updated, err := client.CoreV1().Services("ns").Get(context.Background(), "svc", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("couldn't get service: %v", err)
+	}
+	for _, key := range []string{IdledAtAnnotation, IdledAtReplicasAnnotation, IdledTargetAnnotation} {
+		if _, ok := updated.Annotations[key]; ok {
+			t.Fatalf("annotation %s still present after Activate, idleStatusController and the auto-idler need it cleared to re-arm", key)
+		}
+	}
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,271 @@
+package unidling
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	libovsdbclient "github.com/ovn-org/libovsdb/client"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
+	kapi "k8s.io/api/core/v1"
+	discovery "k8s.io/api/discovery/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	coreinformers "k8s.io/client-go/informers/core/v1"
+	discoveryinformers "k8s.io/client-go/informers/discovery/v1"
+	v1 "k8s.io/client-go/listers/core/v1"
+	discoverylisters "k8s.io/client-go/listers/discovery/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// IdledAtAnnotation records the time a Service's workload was scaled to
+	// zero, in RFC3339.
+	IdledAtAnnotation = "k8s.ovn.org/idled-at"
+
+	// IdleAfterAnnotation overrides InactivityPolicy.Default for a single
+	// Service, parsed as a time.Duration (e.g. "10m").
+	IdleAfterAnnotation = "k8s.ovn.org/idle-after"
+)
+
+// InactivityPolicy controls how long a Service's workload may sit with no
+// ready endpoints and no incoming connections before it's scaled to zero.
+type InactivityPolicy struct {
+	// Default is the inactivity window used for Services that don't carry
+	// IdleAfterAnnotation.
+	Default time.Duration
+}
+
+// idleAfter returns the configured inactivity window for svc.
+func (p InactivityPolicy) idleAfter(svc *kapi.Service) (time.Duration, error) {
+	raw, ok := svc.Annotations[IdleAfterAnnotation]
+	if !ok || raw == "" {
+		return p.Default, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+// autoIdlerController watches EndpointSlices and scales a Service's
+// workload down to zero once it's had no ready endpoints and no incoming
+// connections for its configured InactivityPolicy window, stamping
+// IdledAtAnnotation and IdledAtReplicasAnnotation so the rest of the
+// unidling machinery can bring it back up later. Incoming connections are
+// not measured: hasRecentConnections always reports none, so the decision
+// rests on endpoint readiness alone.
+type autoIdlerController struct {
+	kube                kube.Interface
+	serviceLister       v1.ServiceLister
+	endpointSliceLister discoverylisters.EndpointSliceLister
+	// sbClient is reserved for hasRecentConnections, which will read a
+	// per-VIP connection counter from SBDB once the schema exposes one.
+	sbClient libovsdbclient.Client
+	policy   InactivityPolicy
+	resolver *WorkloadResolver
+
+	// queue holds service namespaced names whose inactivity window has to
+	// be rechecked; AddAfter acts as the fallback timer keyed off
+	// endpoint-transition timestamps.
+	queue workqueue.DelayingInterface
+}
+
+// NewAutoIdlerController creates the automatic idler. resolver is shared
+// with the Activator so both sides agree on how a Service maps to a
+// workload.
+func NewAutoIdlerController(k kube.Interface, serviceInformer coreinformers.ServiceInformer, endpointSliceInformer discoveryinformers.EndpointSliceInformer, sbClient libovsdbclient.Client, resolver *WorkloadResolver, policy InactivityPolicy) *autoIdlerController {
+	aic := &autoIdlerController{
+		kube:                k,
+		serviceLister:       serviceInformer.Lister(),
+		endpointSliceLister: endpointSliceInformer.Lister(),
+		sbClient:            sbClient,
+		policy:              policy,
+		resolver:            resolver,
+		queue:               workqueue.NewDelayingQueue(),
+	}
+
+	endpointSliceInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    aic.onEndpointSliceChange,
+		UpdateFunc: func(_, new interface{}) { aic.onEndpointSliceChange(new) },
+		DeleteFunc: aic.onEndpointSliceChange,
+	})
+
+	return aic
+}
+
+func (aic *autoIdlerController) Run(stopCh <-chan struct{}) {
+	klog.Infof("Starting auto-idler queue worker")
+
+	wait.Until(aic.worker, time.Second, stopCh)
+
+	klog.Infof("Shut auto-idler queue worker")
+	aic.queue.ShutDown()
+}
+
+func (aic *autoIdlerController) worker() {
+	for aic.processNextWorkItem() {
+	}
+}
+
+func (aic *autoIdlerController) processNextWorkItem() bool {
+	key, shutdown := aic.queue.Get()
+
+	if shutdown {
+		return false
+	}
+	defer aic.queue.Done(key)
+
+	if err := aic.syncService(key.(string)); err != nil {
+		utilruntime.HandleError(err)
+	}
+
+	return true
+}
+
+func (aic *autoIdlerController) onEndpointSliceChange(obj interface{}) {
+	eps, ok := obj.(*discovery.EndpointSlice)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("couldn't get object from tombstone %#v", obj))
+			return
+		}
+		eps, ok = tombstone.Obj.(*discovery.EndpointSlice)
+		if !ok {
+			utilruntime.HandleError(fmt.Errorf("tombstone contained object that is not an EndpointSlice: %#v", obj))
+			return
+		}
+	}
+
+	svcName, ok := eps.Labels[discovery.LabelServiceName]
+	if !ok {
+		return
+	}
+
+	if aic.hasReadyEndpoints(eps.Namespace, svcName) {
+		return
+	}
+
+	svc, err := aic.serviceLister.Services(eps.Namespace).Get(svcName)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			utilruntime.HandleError(err)
+		}
+		return
+	}
+
+	if GetIdleStatus(svc) != StatusNotIdle {
+		// Already idle, or transitioning through the grace period.
+		return
+	}
+
+	idleAfter, err := aic.policy.idleAfter(svc)
+	if err != nil {
+		utilruntime.HandleError(fmt.Errorf("invalid %s annotation on service %s/%s: %v", IdleAfterAnnotation, svc.Namespace, svc.Name, err))
+		return
+	}
+
+	key, err := cache.MetaNamespaceKeyFunc(svc)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+
+	klog.V(5).Infof("Service %s has no ready endpoints, rechecking for auto-idle in %s", key, idleAfter)
+	aic.queue.AddAfter(key, idleAfter)
+}
+
+// hasReadyEndpoints reports whether any EndpointSlice backing the named
+// Service currently carries a ready endpoint.
+func (aic *autoIdlerController) hasReadyEndpoints(namespace, name string) bool {
+	slices, err := aic.endpointSliceLister.EndpointSlices(namespace).List(labels.SelectorFromSet(labels.Set{discovery.LabelServiceName: name}))
+	if err != nil {
+		utilruntime.HandleError(err)
+		// Fail safe: don't idle a service we couldn't confirm is empty.
+		return true
+	}
+
+	for _, slice := range slices {
+		for _, ep := range slice.Endpoints {
+			if ep.Conditions.Ready == nil || *ep.Conditions.Ready {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// hasRecentConnections reports whether the VIPs fronting svc have seen
+// traffic recently. The SBDB Load_Balancer schema doesn't currently expose a
+// per-VIP connection counter to read this from, so auto-idle decisions rely
+// solely on hasReadyEndpoints and the idleAfter timer below; this always
+// returns false until that counter exists.
+//
+// TODO: implement the SBDB per-VIP connection-counter check once the
+// Load_Balancer schema exposes one. Until then, idling decisions only look
+// at endpoint readiness, so a Service with ready endpoints but zero traffic
+// is not idled purely on inactivity.
+func (aic *autoIdlerController) hasRecentConnections(svc *kapi.Service) bool {
+	return false
+}
+
+func (aic *autoIdlerController) syncService(key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	svc, err := aic.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	if GetIdleStatus(svc) != StatusNotIdle {
+		return nil
+	}
+
+	if aic.hasReadyEndpoints(namespace, name) || aic.hasRecentConnections(svc) {
+		return nil
+	}
+
+	return aic.idleService(svc)
+}
+
+// idleService scales the workload backing svc down to zero and stamps the
+// annotations the rest of the unidling machinery needs to bring it back.
+func (aic *autoIdlerController) idleService(svc *kapi.Service) error {
+	kind, name, err := aic.resolver.resolveTarget(svc)
+	if err != nil {
+		return fmt.Errorf("couldn't resolve workload backing service %s/%s: %v", svc.Namespace, svc.Name, err)
+	}
+	if kind == "" {
+		klog.Warningf("Could not determine the workload backing service %s/%s, skipping auto-idle", svc.Namespace, svc.Name)
+		return nil
+	}
+
+	replicas, err := aic.resolver.currentReplicas(svc.Namespace, kind, name)
+	if err != nil {
+		return fmt.Errorf("couldn't read replica count for %s %s/%s: %v", kind, svc.Namespace, name, err)
+	}
+	if replicas == 0 {
+		return nil
+	}
+
+	klog.Infof("Idling service %s/%s: scaling %s %s down from %d replicas", svc.Namespace, svc.Name, kind, name, replicas)
+
+	if err := aic.resolver.scaleTo(svc.Namespace, kind, name, 0); err != nil {
+		return fmt.Errorf("couldn't scale %s %s/%s to zero: %v", kind, svc.Namespace, name, err)
+	}
+
+	return aic.kube.SetAnnotationsOnService(svc.Namespace, svc.Name, map[string]interface{}{
+		IdledAtAnnotation:         time.Now().Format(time.RFC3339),
+		IdledAtReplicasAnnotation: strconv.Itoa(int(replicas)),
+		IdledTargetAnnotation:     fmt.Sprintf("%s/%s", kind, name),
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/kube"
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/metrics"
 	kapi "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
@@ -147,6 +148,8 @@ func (isc *idleStatusController) onServiceDelete(obj interface{}) error {
 }
 
 func (isc *idleStatusController) markServiceAsGracePeriod(svc *kapi.Service) error {
+	from := GetIdleStatus(svc)
+
 	// Service has been unidled, put it in the grace period
 	err := isc.kube.SetAnnotationsOnService(svc.Namespace, svc.Name, map[string]interface{}{
 		StatusAnnotation: StatusGracePeriod,
@@ -154,17 +157,19 @@ func (isc *idleStatusController) markServiceAsGracePeriod(svc *kapi.Service) err
 	if err != nil {
 		return fmt.Errorf("can't set service idle status to [%s]: %w", StatusGracePeriod, err)
 	}
+	metrics.MetricUnidlingTransitionsTotal.WithLabelValues(string(from), StatusGracePeriod).Inc()
 
 	key, err := cache.MetaNamespaceKeyFunc(svc)
 	if err != nil {
 		return fmt.Errorf("couldn't get key for service %+v: %v", svc, err)
 	}
 
-	isc.gracePeriodQueue.AddAfter(key, isc.gracePeriod)
+	isc.gracePeriodQueue.AddAfter(key, gracePeriodFor(svc, isc.gracePeriod))
 	return nil
 }
 
 func (isc *idleStatusController) markServiceAsIdleIfNeeded(svc *kapi.Service) error {
+	from := GetIdleStatus(svc)
 
 	if svc.Annotations != nil {
 		status, ok := svc.Annotations[StatusAnnotation]
@@ -181,6 +186,13 @@ func (isc *idleStatusController) markServiceAsIdleIfNeeded(svc *kapi.Service) er
 	if err != nil {
 		return fmt.Errorf("can't set service idle status to [%s]: %w", StatusIdle, err)
 	}
+	metrics.MetricUnidlingTransitionsTotal.WithLabelValues(string(from), StatusIdle).Inc()
+	if from == StatusNotIdle {
+		// Re-idling during the grace period (GracePeriod -> Idle) already
+		// counted toward the gauge on the original NotIdle -> Idle
+		// transition; only a fresh idle period should increment it.
+		metrics.MetricServicesIdle.Inc()
+	}
 
 	return nil
 }
@@ -208,6 +220,8 @@ func (isc *idleStatusController) markServiceAsNotIdle(svc *kapi.Service) error {
 	if err != nil {
 		return fmt.Errorf("can't set service idle status to [%s]: %w", StatusNotIdle, err)
 	}
+	metrics.MetricUnidlingTransitionsTotal.WithLabelValues(status, StatusNotIdle).Inc()
+	metrics.MetricServicesIdle.Dec()
 
 	return nil
 }
@@ -18,8 +18,11 @@ import (
 
 	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/util"
 	kapi "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	v1 "k8s.io/client-go/listers/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
@@ -34,39 +37,99 @@ const (
 	StatusGracePeriod   = "GracePeriod"
 	StatusNotIdle       = "NotIdle"
 	GracePeriodDuration = 30 * time.Second
+
+	// GracePeriodAnnotation overrides GracePeriodDuration (or whatever
+	// default a controller was configured with) for a single Service,
+	// parsed as a time.Duration (e.g. "1m30s").
+	GracePeriodAnnotation = "k8s.ovn.org/idle-grace-period"
+
+	// DefaultUnidlingWorkers is used when Run is called with workers < 1.
+	// Wire the actual worker count from the --unidling-workers CLI flag.
+	DefaultUnidlingWorkers = 1
 )
 
-// unidlingController checks periodically the OVN events db
-// and generates a Kubernetes NeedPods events with the Service
-// associated to the VIP
+// gracePeriodFor returns the grace period to apply to svc: the value of
+// GracePeriodAnnotation if present and valid, otherwise def.
+func gracePeriodFor(svc *kapi.Service, def time.Duration) time.Duration {
+	raw, ok := svc.Annotations[GracePeriodAnnotation]
+	if !ok || raw == "" {
+		return def
+	}
+
+	gracePeriod, err := time.ParseDuration(raw)
+	if err != nil {
+		klog.Warningf("Ignoring invalid %s annotation %q on service %s/%s: %v", GracePeriodAnnotation, raw, svc.Namespace, svc.Name, err)
+		return def
+	}
+
+	return gracePeriod
+}
+
+// unidlingController watches the OVN SB events db and generates a
+// Kubernetes NeedPods event, and optionally an Activator scale-up, for the
+// Service associated with an empty-lb-backends VIP.
 type unidlingController struct {
-	eventQueue    chan sbdb.ControllerEvent
 	eventRecorder record.EventRecorder
 	// Map of load balancers to service namespace
 	serviceVIPToName     map[ServiceVIPKey]types.NamespacedName
 	serviceVIPToNameLock sync.Mutex
 	sbClient             libovsdbclient.Client
-	gracePeriodQueue     workqueue.DelayingInterface
+
+	// queue carries ServiceVIPKeys needing an empty-lb-backends sync.
+	// Duplicate events for the same VIP coalesce onto a single queue entry
+	// via pendingEvents, and a failed sync is requeued with exponential
+	// backoff; the workqueue itself guarantees a key is never handed to two
+	// workers at once, so a single service is never processed concurrently.
+	queue             workqueue.RateLimitingInterface
+	pendingEvents     map[ServiceVIPKey]sbdb.ControllerEvent
+	pendingEventsLock sync.Mutex
+	gracePeriodQueue  workqueue.DelayingInterface
+	// gracePeriodDefault is used for Services that don't carry
+	// GracePeriodAnnotation.
+	gracePeriodDefault time.Duration
+	serviceLister      v1.ServiceLister
+	// activator scales the workload backing a Service back up once it
+	// needs pods again. It may be nil, in which case NeedPods events are
+	// still emitted but nothing is scaled automatically.
+	activator Activator
+
+	// pendingActivations tracks, per service VIP, the time an
+	// empty-lb-backends event was handled for it, so that
+	// MetricUnidlingActivationSeconds can be observed once the first ready
+	// endpoint reappears.
+	pendingActivations     map[ServiceVIPKey]time.Time
+	pendingActivationsLock sync.Mutex
 }
 
-// NewController creates a new unidling controller
-func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIndexInformer, sbClient libovsdbclient.Client) (*unidlingController, error) {
+// NewController creates a new unidling controller. gracePeriod is the
+// default grace period used for Services that don't override it via
+// GracePeriodAnnotation.
+func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIndexInformer, endpointsInformer cache.SharedIndexInformer, sbClient libovsdbclient.Client, activator Activator, gracePeriod time.Duration) (*unidlingController, error) {
+	if gracePeriod == 0 {
+		gracePeriod = GracePeriodDuration
+	}
+
 	uc := &unidlingController{
-		eventQueue:       make(chan sbdb.ControllerEvent),
-		eventRecorder:    recorder,
-		serviceVIPToName: map[ServiceVIPKey]types.NamespacedName{},
-		sbClient:         sbClient,
-		gracePeriodQueue: workqueue.NewDelayingQueue(),
+		eventRecorder:      recorder,
+		serviceVIPToName:   map[ServiceVIPKey]types.NamespacedName{},
+		sbClient:           sbClient,
+		queue:              workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		pendingEvents:      map[ServiceVIPKey]sbdb.ControllerEvent{},
+		gracePeriodQueue:   workqueue.NewDelayingQueue(),
+		gracePeriodDefault: gracePeriod,
+		serviceLister:      v1.NewServiceLister(serviceInformer.GetIndexer()),
+		activator:          activator,
+		pendingActivations: map[ServiceVIPKey]time.Time{},
 	}
 
 	klog.Info("Registering OVN SB ControllerEvent handler")
-	// add all empty lb backend events to a channel
+	// enqueue all empty lb backend events onto the workqueue
 	sbClient.Cache().AddEventHandler(
 		&libovsdbcache.EventHandlerFuncs{
 			AddFunc: func(table string, m model.Model) {
 				if event, ok := m.(*sbdb.ControllerEvent); ok {
 					if event.EventType == sbdb.ControllerEventEventTypeEmptyLbBackends {
-						uc.eventQueue <- *event
+						uc.enqueueEvent(*event)
 					}
 				}
 			},
@@ -85,11 +148,9 @@ func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIn
 	if err != nil {
 		return nil, err
 	}
-	go func() {
-		for _, event := range controllerEvents {
-			uc.eventQueue <- event
-		}
-	}()
+	for _, event := range controllerEvents {
+		uc.enqueueEvent(event)
+	}
 
 	// we only process events on unidling, there is no reconcilation
 	klog.Info("Setting up event handlers for services")
@@ -101,6 +162,13 @@ func NewController(recorder record.EventRecorder, serviceInformer cache.SharedIn
 		},
 		DeleteFunc: uc.onServiceDelete,
 	})
+
+	klog.Info("Setting up event handlers for endpoints")
+	endpointsInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    uc.onEndpointsChange,
+		UpdateFunc: func(_, new interface{}) { uc.onEndpointsChange(new) },
+	})
+
 	return uc, nil
 }
 
@@ -160,7 +228,7 @@ func (uc *unidlingController) alignIdleStatusAnnotation(svc *kapi.Service) error
 		if err != nil {
 			return fmt.Errorf("couldn't get key for service %+v: %v", svc, err)
 		}
-		uc.gracePeriodQueue.AddAfter(key, GracePeriodDuration)
+		uc.gracePeriodQueue.AddAfter(key, gracePeriodFor(svc, uc.gracePeriodDefault))
 
 	case StatusNotIdle:
 		if !hasIdledAtAnnotation {
@@ -240,24 +308,118 @@ func (uc *unidlingController) DeleteServiceVIPToName(vip string, protocol kapi.P
 	delete(uc.serviceVIPToName, ServiceVIPKey{vip, protocol})
 }
 
-func (uc *unidlingController) Run(stopCh <-chan struct{}) {
-	for {
-		select {
-		case event := <-uc.eventQueue:
-			if err := uc.handleLbEmptyBackendsEvent(event); err != nil {
-				klog.Error(err)
-			}
-		case event := <-uc.gracePeriodQueue:
-			if err := uc.handleGracePeriodEndEvent(event); err != nil {
-				klog.Error(err)
-			}
-		case <-stopCh:
-			return
-		}
+// Run starts the unidling controller. workers sets how many NeedPods
+// events can be processed in parallel; wire it from the
+// --unidling-workers CLI flag. Values below 1 fall back to
+// DefaultUnidlingWorkers.
+func (uc *unidlingController) Run(workers int, stopCh <-chan struct{}) {
+	defer utilruntime.HandleCrash()
+	defer uc.queue.ShutDown()
+	defer uc.gracePeriodQueue.ShutDown()
+
+	if workers < 1 {
+		workers = DefaultUnidlingWorkers
+	}
+
+	klog.Infof("Starting %d unidling workers", workers)
+	for i := 0; i < workers; i++ {
+		go wait.Until(uc.runWorker, time.Second, stopCh)
+	}
+
+	go wait.Until(uc.gracePeriodWorker, time.Second, stopCh)
+
+	<-stopCh
+	klog.Info("Shutting down unidling controller")
+}
+
+// enqueueEvent coalesces event onto the workqueue by its ServiceVIPKey: a
+// second empty-lb-backends event for the same VIP before the first is
+// processed just replaces the stored event and leaves a single queue entry.
+func (uc *unidlingController) enqueueEvent(event sbdb.ControllerEvent) {
+	vip, protocol, ok := vipAndProtocolFromEvent(event)
+	if !ok {
+		klog.Warningf("Ignoring ControllerEvent %s with no vip in its info", event.UUID)
+		return
+	}
+
+	key := ServiceVIPKey{vip, protocol}
+
+	uc.pendingEventsLock.Lock()
+	uc.pendingEvents[key] = event
+	uc.pendingEventsLock.Unlock()
+
+	uc.queue.Add(key)
+}
+
+func vipAndProtocolFromEvent(event sbdb.ControllerEvent) (string, kapi.Protocol, bool) {
+	vip, ok := event.EventInfo["vip"]
+	if !ok {
+		return "", "", false
+	}
+
+	switch event.EventInfo["protocol"] {
+	case "udp":
+		return vip, kapi.ProtocolUDP, true
+	case "sctp":
+		return vip, kapi.ProtocolSCTP, true
+	default:
+		return vip, kapi.ProtocolTCP, true
+	}
+}
+
+func (uc *unidlingController) runWorker() {
+	for uc.processNextWorkItem() {
+	}
+}
+
+func (uc *unidlingController) processNextWorkItem() bool {
+	key, shutdown := uc.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer uc.queue.Done(key)
+
+	if err := uc.syncVIP(key.(ServiceVIPKey)); err != nil {
+		utilruntime.HandleError(fmt.Errorf("error processing empty-lb-backends event for %v, requeuing: %v", key, err))
+		uc.queue.AddRateLimited(key)
+		return true
+	}
+
+	uc.queue.Forget(key)
+	return true
+}
+
+// syncVIP handles the latest empty-lb-backends event coalesced for key. It's
+// a no-op if the event was already consumed by an earlier, coalesced run.
+//
+// The pendingEvents entry is only cleared once handleLbEmptyBackendsEvent
+// succeeds: a failed sync is requeued by processNextWorkItem via
+// AddRateLimited, and the retry needs to find the same event still there.
+func (uc *unidlingController) syncVIP(key ServiceVIPKey) error {
+	uc.pendingEventsLock.Lock()
+	event, ok := uc.pendingEvents[key]
+	uc.pendingEventsLock.Unlock()
+
+	if !ok {
+		return nil
+	}
+
+	if err := uc.handleLbEmptyBackendsEvent(key, event); err != nil {
+		return err
+	}
+
+	uc.pendingEventsLock.Lock()
+	// Only clear the entry we just handled: if a newer event for the same
+	// key coalesced in while we were processing, leave it for the next run.
+	if uc.pendingEvents[key].UUID == event.UUID {
+		delete(uc.pendingEvents, key)
 	}
+	uc.pendingEventsLock.Unlock()
+
+	return nil
 }
 
-func (uc *unidlingController) handleLbEmptyBackendsEvent(event sbdb.ControllerEvent) error {
+func (uc *unidlingController) handleLbEmptyBackendsEvent(key ServiceVIPKey, event sbdb.ControllerEvent) error {
 	op, err := uc.sbClient.Where(
 		&event,
 	).Delete()
@@ -274,20 +436,8 @@ func (uc *unidlingController) handleLbEmptyBackendsEvent(event sbdb.ControllerEv
 	if err != nil {
 		return err
 	}
-	vip, ok := event.EventInfo["vip"]
-	if !ok {
-		return err
-	}
-	proto := event.EventInfo["protocol"]
-	var protocol kapi.Protocol
-	if proto == "udp" {
-		protocol = kapi.ProtocolUDP
-	} else if proto == "sctp" {
-		protocol = kapi.ProtocolSCTP
-	} else {
-		protocol = kapi.ProtocolTCP
-	}
-	if serviceName, ok := uc.GetServiceVIPToName(vip, protocol); ok {
+
+	if serviceName, ok := uc.GetServiceVIPToName(key.vip, key.protocol); ok {
 		serviceRef := kapi.ObjectReference{
 			Kind:      "Service",
 			Namespace: serviceName.Namespace,
@@ -295,10 +445,98 @@ func (uc *unidlingController) handleLbEmptyBackendsEvent(event sbdb.ControllerEv
 		}
 		klog.V(5).Infof("Sending a NeedPods event for service %s in namespace %s.", serviceName.Name, serviceName.Namespace)
 		uc.eventRecorder.Eventf(&serviceRef, kapi.EventTypeNormal, "NeedPods", "The service %s needs pods", serviceName.Name)
+		metrics.MetricUnidlingEventsTotal.WithLabelValues(serviceName.Name, serviceName.Namespace, "need_pods").Inc()
+		uc.recordPendingActivation(key)
+
+		if uc.activator != nil {
+			uc.activateService(serviceName)
+		}
+	} else {
+		metrics.MetricUnidlingEventsTotal.WithLabelValues("", "", "unknown_vip").Inc()
 	}
 	return nil
 }
 
+// recordPendingActivation notes the time an empty-lb-backends event was
+// handled for key, so MetricUnidlingActivationSeconds can be observed once
+// the service's first ready endpoint reappears.
+func (uc *unidlingController) recordPendingActivation(key ServiceVIPKey) {
+	uc.pendingActivationsLock.Lock()
+	defer uc.pendingActivationsLock.Unlock()
+	uc.pendingActivations[key] = time.Now()
+}
+
+// onEndpointsChange observes MetricUnidlingActivationSeconds for every VIP
+// awaiting activation on the service that obj belongs to, once it gets its
+// first ready address.
+func (uc *unidlingController) onEndpointsChange(obj interface{}) {
+	ep, ok := obj.(*kapi.Endpoints)
+	if !ok {
+		return
+	}
+
+	hasReadyAddress := false
+	for _, subset := range ep.Subsets {
+		if len(subset.Addresses) > 0 {
+			hasReadyAddress = true
+			break
+		}
+	}
+	if !hasReadyAddress {
+		return
+	}
+
+	name := types.NamespacedName{Namespace: ep.Namespace, Name: ep.Name}
+
+	uc.serviceVIPToNameLock.Lock()
+	var keys []ServiceVIPKey
+	for key, svcName := range uc.serviceVIPToName {
+		if svcName == name {
+			keys = append(keys, key)
+		}
+	}
+	uc.serviceVIPToNameLock.Unlock()
+
+	uc.pendingActivationsLock.Lock()
+	defer uc.pendingActivationsLock.Unlock()
+	for _, key := range keys {
+		start, ok := uc.pendingActivations[key]
+		if !ok {
+			continue
+		}
+		metrics.MetricUnidlingActivationSeconds.Observe(time.Since(start).Seconds())
+		delete(uc.pendingActivations, key)
+	}
+}
+
+// activateService looks up the live Service object for name and asks the
+// configured Activator to scale its backing workload back up.
+func (uc *unidlingController) activateService(name types.NamespacedName) {
+	svc, err := uc.serviceLister.Services(name.Namespace).Get(name.Name)
+	if err != nil {
+		klog.Errorf("Couldn't get service %s/%s to activate it: %v", name.Namespace, name.Name, err)
+		return
+	}
+
+	if err := uc.activator.Activate(svc); err != nil {
+		klog.Errorf("Couldn't activate service %s/%s: %v", name.Namespace, name.Name, err)
+	}
+}
+
+// gracePeriodWorker drains gracePeriodQueue until it's shut down.
+func (uc *unidlingController) gracePeriodWorker() {
+	for {
+		key, shutdown := uc.gracePeriodQueue.Get()
+		if shutdown {
+			return
+		}
+		if err := uc.handleGracePeriodEndEvent(key); err != nil {
+			klog.Error(err)
+		}
+		uc.gracePeriodQueue.Done(key)
+	}
+}
+
 func (uc *unidlingController) handleGracePeriodEndEvent(key interface{}) error {
 	namespace, name, err := cache.SplitMetaNamespaceKey(key.(string))
 	if err != nil {
@@ -306,11 +544,22 @@ func (uc *unidlingController) handleGracePeriodEndEvent(key interface{}) error {
 	}
 	klog.Infof("Unidling grace period finished for service %s/%s", namespace, name)
 
+	startTime := time.Now()
 	defer func() {
 		klog.V(4).Infof("Finished syncing service %s on namespace %s : %v", name, namespace, time.Since(startTime))
 		metrics.MetricSyncServiceLatency.Observe(time.Since(startTime).Seconds())
 	}()
 
 	// Get current Service from the cache
-	service, err := uc.serviceLister.Services(namespace).Get(name)
+	_, err = uc.serviceLister.Services(namespace).Get(name)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			// Service has been deleted during the grace period
+			return nil
+		}
+		return err
+	}
+
+	// TODO - Set annotation
+	return nil
 }
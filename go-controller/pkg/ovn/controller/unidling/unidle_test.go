@@ -0,0 +1,93 @@
+package unidling
+
+import (
+	"testing"
+
+	"github.com/ovn-org/ovn-kubernetes/go-controller/pkg/sbdb"
+	kapi "k8s.io/api/core/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestVipAndProtocolFromEvent(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    sbdb.ControllerEvent
+		wantVIP  string
+		wantProt kapi.Protocol
+		wantOK   bool
+	}{
+		{
+			name:     "tcp is the default protocol",
+			event:    sbdb.ControllerEvent{EventInfo: map[string]string{"vip": "10.0.0.1:80"}},
+			wantVIP:  "10.0.0.1:80",
+			wantProt: kapi.ProtocolTCP,
+			wantOK:   true,
+		},
+		{
+			name:     "udp protocol is honored",
+			event:    sbdb.ControllerEvent{EventInfo: map[string]string{"vip": "10.0.0.1:53", "protocol": "udp"}},
+			wantVIP:  "10.0.0.1:53",
+			wantProt: kapi.ProtocolUDP,
+			wantOK:   true,
+		},
+		{
+			name:   "missing vip is rejected",
+			event:  sbdb.ControllerEvent{EventInfo: map[string]string{}},
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vip, protocol, ok := vipAndProtocolFromEvent(tt.event)
+			if ok != tt.wantOK {
+				t.Fatalf("got ok=%v, want %v", ok, tt.wantOK)
+			}
+			if !ok {
+				return
+			}
+			if vip != tt.wantVIP || protocol != tt.wantProt {
+				t.Fatalf("got %s/%s, want %s/%s", vip, protocol, tt.wantVIP, tt.wantProt)
+			}
+		})
+	}
+}
+
+func TestEnqueueEventCoalescesRepeatEventsForSameVIP(t *testing.T) {
+	uc := &unidlingController{
+		queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		pendingEvents: map[ServiceVIPKey]sbdb.ControllerEvent{},
+	}
+
+	first := sbdb.ControllerEvent{UUID: "event-1", EventInfo: map[string]string{"vip": "10.0.0.1:80"}}
+	second := sbdb.ControllerEvent{UUID: "event-2", EventInfo: map[string]string{"vip": "10.0.0.1:80"}}
+
+	uc.enqueueEvent(first)
+	uc.enqueueEvent(second)
+
+	if n := uc.queue.Len(); n != 1 {
+		t.Fatalf("got %d queue entries, want 1 (repeat events for the same VIP must coalesce)", n)
+	}
+
+	key := ServiceVIPKey{"10.0.0.1:80", kapi.ProtocolTCP}
+	stored, ok := uc.pendingEvents[key]
+	if !ok {
+		t.Fatalf("no pending event stored for %v", key)
+	}
+	if stored.UUID != second.UUID {
+		t.Fatalf("got pending event %s, want the latest event %s", stored.UUID, second.UUID)
+	}
+}
+
+func TestEnqueueEventIgnoresEventsWithoutVIP(t *testing.T) {
+	uc := &unidlingController{
+		queue:         workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		pendingEvents: map[ServiceVIPKey]sbdb.ControllerEvent{},
+	}
+
+	uc.enqueueEvent(sbdb.ControllerEvent{UUID: "no-vip", EventInfo: map[string]string{}})
+
+	if n := uc.queue.Len(); n != 0 {
+		t.Fatalf("got %d queue entries, want 0 for an event with no vip", n)
+	}
+}